@@ -0,0 +1,256 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package protocol
+
+import (
+	"encoding/binary"
+	"io"
+	"math"
+
+	"github.com/uber/thriftrw-go/wire"
+)
+
+// Reader returns a ValueReader that pulls values directly off r using
+// the Binary Protocol's wire format, without materializing a
+// wire.Value tree.
+func (binaryProtocol) Reader(r io.Reader) ValueReader {
+	return &binaryValueReader{reader: r}
+}
+
+// Writer returns a ValueWriter that pushes values directly onto w using
+// the Binary Protocol's wire format.
+func (binaryProtocol) Writer(w io.Writer) ValueWriter {
+	return &binaryValueWriter{writer: w}
+}
+
+type binaryValueReader struct{ reader io.Reader }
+
+func (r *binaryValueReader) readN(n int) ([]byte, error) {
+	buf := make([]byte, n)
+	if _, err := io.ReadFull(r.reader, buf); err != nil {
+		return nil, err
+	}
+	return buf, nil
+}
+
+// Struct and collection begin/end markers have no on-the-wire
+// representation in the Binary Protocol; they exist purely so callers
+// can write format-agnostic marshalers against ValueReader/ValueWriter.
+func (r *binaryValueReader) ReadStructBegin() error { return nil }
+func (r *binaryValueReader) ReadStructEnd() error   { return nil }
+func (r *binaryValueReader) ReadFieldEnd() error    { return nil }
+func (r *binaryValueReader) ReadListEnd() error     { return nil }
+func (r *binaryValueReader) ReadSetEnd() error      { return nil }
+func (r *binaryValueReader) ReadMapEnd() error      { return nil }
+
+func (r *binaryValueReader) ReadFieldBegin() (id int16, typ wire.Type, ok bool, err error) {
+	b, err := r.readN(1)
+	if err != nil {
+		return 0, 0, false, err
+	}
+	if b[0] == 0x00 {
+		return 0, 0, false, nil
+	}
+	typ = wire.Type(b[0])
+
+	idBytes, err := r.readN(2)
+	if err != nil {
+		return 0, 0, false, err
+	}
+	return int16(binary.BigEndian.Uint16(idBytes)), typ, true, nil
+}
+
+func (r *binaryValueReader) ReadListBegin() (wire.Type, int, error) {
+	return r.readCollectionBegin()
+}
+
+func (r *binaryValueReader) ReadSetBegin() (wire.Type, int, error) {
+	return r.readCollectionBegin()
+}
+
+func (r *binaryValueReader) readCollectionBegin() (wire.Type, int, error) {
+	b, err := r.readN(1)
+	if err != nil {
+		return 0, 0, err
+	}
+	size, err := r.ReadI32()
+	if err != nil {
+		return 0, 0, err
+	}
+	return wire.Type(b[0]), int(size), nil
+}
+
+func (r *binaryValueReader) ReadMapBegin() (keyType, valueType wire.Type, size int, err error) {
+	types, err := r.readN(2)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	n, err := r.ReadI32()
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	return wire.Type(types[0]), wire.Type(types[1]), int(n), nil
+}
+
+func (r *binaryValueReader) ReadBool() (bool, error) {
+	b, err := r.readN(1)
+	if err != nil {
+		return false, err
+	}
+	return b[0] != 0, nil
+}
+
+func (r *binaryValueReader) ReadI8() (int8, error) {
+	b, err := r.readN(1)
+	if err != nil {
+		return 0, err
+	}
+	return int8(b[0]), nil
+}
+
+func (r *binaryValueReader) ReadI16() (int16, error) {
+	b, err := r.readN(2)
+	if err != nil {
+		return 0, err
+	}
+	return int16(binary.BigEndian.Uint16(b)), nil
+}
+
+func (r *binaryValueReader) ReadI32() (int32, error) {
+	b, err := r.readN(4)
+	if err != nil {
+		return 0, err
+	}
+	return int32(binary.BigEndian.Uint32(b)), nil
+}
+
+func (r *binaryValueReader) ReadI64() (int64, error) {
+	b, err := r.readN(8)
+	if err != nil {
+		return 0, err
+	}
+	return int64(binary.BigEndian.Uint64(b)), nil
+}
+
+func (r *binaryValueReader) ReadDouble() (float64, error) {
+	b, err := r.readN(8)
+	if err != nil {
+		return 0, err
+	}
+	return math.Float64frombits(binary.BigEndian.Uint64(b)), nil
+}
+
+func (r *binaryValueReader) ReadBinary() ([]byte, error) {
+	size, err := r.ReadI32()
+	if err != nil {
+		return nil, err
+	}
+	return r.readN(int(size))
+}
+
+type binaryValueWriter struct{ writer io.Writer }
+
+func (w *binaryValueWriter) write(b []byte) error {
+	_, err := w.writer.Write(b)
+	return err
+}
+
+func (w *binaryValueWriter) WriteStructBegin() error { return nil }
+func (w *binaryValueWriter) WriteStructEnd() error   { return nil }
+func (w *binaryValueWriter) WriteFieldEnd() error    { return nil }
+func (w *binaryValueWriter) WriteListEnd() error     { return nil }
+func (w *binaryValueWriter) WriteSetEnd() error      { return nil }
+func (w *binaryValueWriter) WriteMapEnd() error      { return nil }
+
+func (w *binaryValueWriter) WriteFieldBegin(id int16, typ wire.Type) error {
+	var buf [3]byte
+	buf[0] = byte(typ)
+	binary.BigEndian.PutUint16(buf[1:], uint16(id))
+	return w.write(buf[:])
+}
+
+func (w *binaryValueWriter) WriteFieldStop() error {
+	return w.write([]byte{0x00})
+}
+
+func (w *binaryValueWriter) WriteListBegin(typ wire.Type, size int) error {
+	return w.writeCollectionBegin(typ, size)
+}
+
+func (w *binaryValueWriter) WriteSetBegin(typ wire.Type, size int) error {
+	return w.writeCollectionBegin(typ, size)
+}
+
+func (w *binaryValueWriter) writeCollectionBegin(typ wire.Type, size int) error {
+	if err := w.write([]byte{byte(typ)}); err != nil {
+		return err
+	}
+	return w.WriteI32(int32(size))
+}
+
+func (w *binaryValueWriter) WriteMapBegin(keyType, valueType wire.Type, size int) error {
+	if err := w.write([]byte{byte(keyType), byte(valueType)}); err != nil {
+		return err
+	}
+	return w.WriteI32(int32(size))
+}
+
+func (w *binaryValueWriter) WriteBool(b bool) error {
+	if b {
+		return w.write([]byte{0x01})
+	}
+	return w.write([]byte{0x00})
+}
+
+func (w *binaryValueWriter) WriteI8(b int8) error {
+	return w.write([]byte{byte(b)})
+}
+
+func (w *binaryValueWriter) WriteI16(v int16) error {
+	var buf [2]byte
+	binary.BigEndian.PutUint16(buf[:], uint16(v))
+	return w.write(buf[:])
+}
+
+func (w *binaryValueWriter) WriteI32(v int32) error {
+	var buf [4]byte
+	binary.BigEndian.PutUint32(buf[:], uint32(v))
+	return w.write(buf[:])
+}
+
+func (w *binaryValueWriter) WriteI64(v int64) error {
+	var buf [8]byte
+	binary.BigEndian.PutUint64(buf[:], uint64(v))
+	return w.write(buf[:])
+}
+
+func (w *binaryValueWriter) WriteDouble(v float64) error {
+	var buf [8]byte
+	binary.BigEndian.PutUint64(buf[:], math.Float64bits(v))
+	return w.write(buf[:])
+}
+
+func (w *binaryValueWriter) WriteBinary(b []byte) error {
+	if err := w.WriteI32(int32(len(b))); err != nil {
+		return err
+	}
+	return w.write(b)
+}
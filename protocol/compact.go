@@ -0,0 +1,555 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package protocol
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+
+	"github.com/uber/thriftrw-go/wire"
+)
+
+// Compact implements the Thrift Compact Protocol, an alternative to
+// Binary that favors smaller payloads over simplicity: integers are
+// varint/zigzag encoded, struct field headers delta-encode the field
+// ID against the previous one, and collection headers pack small sizes
+// and element types into a single byte.
+//
+// Compact is wire-compatible with TCompactProtocol implementations in
+// other Thrift bindings.
+var Compact Protocol = compactProtocol{}
+
+type compactProtocol struct{}
+
+// Compact type IDs. These are distinct from wire.Type; the compact
+// protocol renumbers types so that the common ones fit in fewer bits
+// and so that boolean field values can be folded into the field header.
+const (
+	compactStop         byte = 0x00
+	compactBooleanTrue  byte = 0x01
+	compactBooleanFalse byte = 0x02
+	compactByte         byte = 0x03
+	compactI16          byte = 0x04
+	compactI32          byte = 0x05
+	compactI64          byte = 0x06
+	compactDouble       byte = 0x07
+	compactBinary       byte = 0x08
+	compactList         byte = 0x09
+	compactSet          byte = 0x0A
+	compactMap          byte = 0x0B
+	compactStruct       byte = 0x0C
+)
+
+// errCompactNegativeSize is returned when encoding a list or set whose
+// Size is negative. Decoding does not return it.
+var errCompactNegativeSize = errors.New("compact: collection has a negative size")
+
+func compactTypeFromWire(t wire.Type) (byte, error) {
+	switch t {
+	case wire.TBool:
+		return compactBooleanTrue, nil
+	case wire.TByte:
+		return compactByte, nil
+	case wire.TI16:
+		return compactI16, nil
+	case wire.TI32:
+		return compactI32, nil
+	case wire.TI64:
+		return compactI64, nil
+	case wire.TDouble:
+		return compactDouble, nil
+	case wire.TBinary:
+		return compactBinary, nil
+	case wire.TStruct:
+		return compactStruct, nil
+	case wire.TMap:
+		return compactMap, nil
+	case wire.TSet:
+		return compactSet, nil
+	case wire.TList:
+		return compactList, nil
+	default:
+		return 0, fmt.Errorf("compact: unknown type %v", t)
+	}
+}
+
+func wireTypeFromCompact(b byte) (wire.Type, error) {
+	switch b {
+	case compactBooleanTrue, compactBooleanFalse:
+		return wire.TBool, nil
+	case compactByte:
+		return wire.TByte, nil
+	case compactI16:
+		return wire.TI16, nil
+	case compactI32:
+		return wire.TI32, nil
+	case compactI64:
+		return wire.TI64, nil
+	case compactDouble:
+		return wire.TDouble, nil
+	case compactBinary:
+		return wire.TBinary, nil
+	case compactStruct:
+		return wire.TStruct, nil
+	case compactMap:
+		return wire.TMap, nil
+	case compactSet:
+		return wire.TSet, nil
+	case compactList:
+		return wire.TList, nil
+	default:
+		return 0, fmt.Errorf("compact: unknown compact type %#x", b)
+	}
+}
+
+// Encode writes the given Value to the given Writer using the Compact
+// Protocol.
+func (compactProtocol) Encode(value wire.Value, writer io.Writer) error {
+	cw := compactWriter{writer: writer}
+	return cw.writeValue(value)
+}
+
+// Decode reads a Value of the given type from the given Reader using
+// the Compact Protocol.
+func (compactProtocol) Decode(reader io.Reader, t wire.Type) (wire.Value, error) {
+	cr := compactReader{reader: reader}
+	return cr.readValue(t)
+}
+
+type compactWriter struct{ writer io.Writer }
+
+func (cw compactWriter) writeByte(b byte) error {
+	_, err := cw.writer.Write([]byte{b})
+	return err
+}
+
+func (cw compactWriter) writeVarint(v uint64) error {
+	var buf [10]byte
+	i := 0
+	for {
+		if v&^0x7f == 0 {
+			buf[i] = byte(v)
+			i++
+			break
+		}
+		buf[i] = byte(v&0x7f) | 0x80
+		v >>= 7
+		i++
+	}
+	_, err := cw.writer.Write(buf[:i])
+	return err
+}
+
+func zigzag32(n int32) uint32 { return uint32(n<<1) ^ uint32(n>>31) }
+func zigzag64(n int64) uint64 { return uint64(n<<1) ^ uint64(n>>63) }
+
+func (cw compactWriter) writeValue(val wire.Value) error {
+	switch val.Type() {
+	case wire.TBool:
+		// Outside a struct field header, a bool is written as its
+		// compact type byte (BOOLEAN_TRUE/BOOLEAN_FALSE), not a plain
+		// 0/1 flag, so that it round-trips through other bindings'
+		// TCompactProtocol implementations.
+		if val.GetBool() {
+			return cw.writeByte(compactBooleanTrue)
+		}
+		return cw.writeByte(compactBooleanFalse)
+	case wire.TByte:
+		return cw.writeByte(byte(val.GetByte()))
+	case wire.TI16:
+		return cw.writeVarint(uint64(zigzag32(int32(val.GetI16()))))
+	case wire.TI32:
+		return cw.writeVarint(uint64(zigzag32(val.GetI32())))
+	case wire.TI64:
+		return cw.writeVarint(zigzag64(val.GetI64()))
+	case wire.TDouble:
+		return cw.writeDouble(val.GetDouble())
+	case wire.TBinary:
+		return cw.writeBinary(val.GetBinary())
+	case wire.TStruct:
+		return cw.writeStruct(val.GetStruct())
+	case wire.TMap:
+		return cw.writeMap(val.GetMap())
+	case wire.TSet:
+		return cw.writeCollection(val.GetSet())
+	case wire.TList:
+		return cw.writeCollection(val.GetList())
+	default:
+		return fmt.Errorf("compact: cannot encode unknown type %v", val.Type())
+	}
+}
+
+func (cw compactWriter) writeDouble(d float64) error {
+	bits := math.Float64bits(d)
+	var buf [8]byte
+	for i := 0; i < 8; i++ {
+		buf[i] = byte(bits >> uint(8*i))
+	}
+	_, err := cw.writer.Write(buf[:])
+	return err
+}
+
+func (cw compactWriter) writeBinary(b []byte) error {
+	if err := cw.writeVarint(uint64(len(b))); err != nil {
+		return err
+	}
+	_, err := cw.writer.Write(b)
+	return err
+}
+
+func (cw compactWriter) writeStruct(s wire.Struct) error {
+	var lastID int16
+	for _, field := range s.Fields {
+		typ, err := compactTypeFromWire(field.Value.Type())
+		if err != nil {
+			return err
+		}
+		if typ == compactBooleanTrue && !field.Value.GetBool() {
+			typ = compactBooleanFalse
+		}
+
+		if err := cw.writeFieldHeader(typ, field.ID, lastID); err != nil {
+			return err
+		}
+		lastID = field.ID
+
+		// Boolean field values are folded into the header above; every
+		// other type still needs its value written out.
+		if typ != compactBooleanTrue && typ != compactBooleanFalse {
+			if err := cw.writeValue(field.Value); err != nil {
+				return err
+			}
+		}
+	}
+	return cw.writeByte(compactStop)
+}
+
+func (cw compactWriter) writeFieldHeader(typ byte, id, lastID int16) error {
+	delta := id - lastID
+	if delta > 0 && delta <= 15 {
+		return cw.writeByte(byte(delta)<<4 | typ)
+	}
+	if err := cw.writeByte(typ); err != nil {
+		return err
+	}
+	return cw.writeVarint(uint64(zigzag32(int32(id))))
+}
+
+func (cw compactWriter) writeCollectionHeader(size int, elemType byte) error {
+	if size < 0 {
+		return errCompactNegativeSize
+	}
+	if size <= 14 {
+		return cw.writeByte(byte(size)<<4 | elemType)
+	}
+	if err := cw.writeByte(0xF0 | elemType); err != nil {
+		return err
+	}
+	return cw.writeVarint(uint64(size))
+}
+
+func (cw compactWriter) writeCollection(l wire.ValueList) error {
+	elemType, err := compactTypeFromWire(l.ValueType)
+	if err != nil {
+		return err
+	}
+	if err := cw.writeCollectionHeader(l.Size, elemType); err != nil {
+		return err
+	}
+	for _, item := range l.Items {
+		if err := cw.writeValue(item); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (cw compactWriter) writeMap(m wire.MapItemList) error {
+	if m.Size == 0 {
+		// Empty map short form: a single zero byte, with no key/value
+		// type nibble since there is nothing to type.
+		return cw.writeByte(0x00)
+	}
+
+	if err := cw.writeVarint(uint64(m.Size)); err != nil {
+		return err
+	}
+
+	keyType, err := compactTypeFromWire(m.KeyType)
+	if err != nil {
+		return err
+	}
+	valueType, err := compactTypeFromWire(m.ValueType)
+	if err != nil {
+		return err
+	}
+	if err := cw.writeByte(keyType<<4 | valueType); err != nil {
+		return err
+	}
+
+	for _, item := range m.Items {
+		if err := cw.writeValue(item.Key); err != nil {
+			return err
+		}
+		if err := cw.writeValue(item.Value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type compactReader struct{ reader io.Reader }
+
+func (cr compactReader) readByte() (byte, error) {
+	var buf [1]byte
+	if _, err := io.ReadFull(cr.reader, buf[:]); err != nil {
+		return 0, err
+	}
+	return buf[0], nil
+}
+
+func (cr compactReader) readVarint() (uint64, error) {
+	var result uint64
+	var shift uint
+	for {
+		b, err := cr.readByte()
+		if err != nil {
+			return 0, err
+		}
+		result |= uint64(b&0x7f) << shift
+		if b&0x80 == 0 {
+			return result, nil
+		}
+		shift += 7
+	}
+}
+
+func unzigzag32(u uint32) int32 { return int32(u>>1) ^ -int32(u&1) }
+func unzigzag64(u uint64) int64 { return int64(u>>1) ^ -int64(u&1) }
+
+func (cr compactReader) readValue(t wire.Type) (wire.Value, error) {
+	switch t {
+	case wire.TBool:
+		// Mirrors writeValue: outside a struct field header, a bool is
+		// its compact type byte, so only BOOLEAN_TRUE means true.
+		b, err := cr.readByte()
+		if err != nil {
+			return wire.Value{}, err
+		}
+		return wire.NewValueBool(b == compactBooleanTrue), nil
+	case wire.TByte:
+		b, err := cr.readByte()
+		if err != nil {
+			return wire.Value{}, err
+		}
+		return wire.NewValueByte(int8(b)), nil
+	case wire.TI16:
+		v, err := cr.readVarint()
+		if err != nil {
+			return wire.Value{}, err
+		}
+		return wire.NewValueI16(int16(unzigzag32(uint32(v)))), nil
+	case wire.TI32:
+		v, err := cr.readVarint()
+		if err != nil {
+			return wire.Value{}, err
+		}
+		return wire.NewValueI32(unzigzag32(uint32(v))), nil
+	case wire.TI64:
+		v, err := cr.readVarint()
+		if err != nil {
+			return wire.Value{}, err
+		}
+		return wire.NewValueI64(unzigzag64(v)), nil
+	case wire.TDouble:
+		return cr.readDouble()
+	case wire.TBinary:
+		return cr.readBinary()
+	case wire.TStruct:
+		return cr.readStruct()
+	case wire.TMap:
+		return cr.readMap()
+	case wire.TSet:
+		return cr.readCollection(wire.NewValueSet)
+	case wire.TList:
+		return cr.readCollection(wire.NewValueList)
+	default:
+		return wire.Value{}, fmt.Errorf("compact: cannot decode unknown type %v", t)
+	}
+}
+
+func (cr compactReader) readDouble() (wire.Value, error) {
+	var buf [8]byte
+	if _, err := io.ReadFull(cr.reader, buf[:]); err != nil {
+		return wire.Value{}, err
+	}
+	var bits uint64
+	for i := 0; i < 8; i++ {
+		bits |= uint64(buf[i]) << uint(8*i)
+	}
+	return wire.NewValueDouble(math.Float64frombits(bits)), nil
+}
+
+func (cr compactReader) readBinary() (wire.Value, error) {
+	size, err := cr.readVarint()
+	if err != nil {
+		return wire.Value{}, err
+	}
+	buf := make([]byte, size)
+	if _, err := io.ReadFull(cr.reader, buf); err != nil {
+		return wire.Value{}, err
+	}
+	return wire.NewValueBinary(buf), nil
+}
+
+func (cr compactReader) readStruct() (wire.Value, error) {
+	var fields []wire.Field
+	var lastID int16
+	for {
+		header, err := cr.readByte()
+		if err != nil {
+			return wire.Value{}, err
+		}
+		if header == compactStop {
+			break
+		}
+
+		typ := header & 0x0F
+		delta := header >> 4
+
+		var id int16
+		if delta == 0 {
+			v, err := cr.readVarint()
+			if err != nil {
+				return wire.Value{}, err
+			}
+			id = int16(unzigzag32(uint32(v)))
+		} else {
+			id = lastID + int16(delta)
+		}
+		lastID = id
+
+		var value wire.Value
+		switch typ {
+		case compactBooleanTrue:
+			value = wire.NewValueBool(true)
+		case compactBooleanFalse:
+			value = wire.NewValueBool(false)
+		default:
+			fieldType, err := wireTypeFromCompact(typ)
+			if err != nil {
+				return wire.Value{}, err
+			}
+			value, err = cr.readValue(fieldType)
+			if err != nil {
+				return wire.Value{}, err
+			}
+		}
+
+		fields = append(fields, wire.Field{ID: id, Value: value})
+	}
+	return wire.NewValueStruct(wire.Struct{Fields: fields}), nil
+}
+
+func (cr compactReader) readCollectionHeader() (size int, elemType byte, err error) {
+	header, err := cr.readByte()
+	if err != nil {
+		return 0, 0, err
+	}
+	elemType = header & 0x0F
+	size = int(header >> 4)
+	if size == 15 {
+		v, err := cr.readVarint()
+		if err != nil {
+			return 0, 0, err
+		}
+		size = int(v)
+	}
+	return size, elemType, nil
+}
+
+func (cr compactReader) readCollection(build func(wire.ValueList) wire.Value) (wire.Value, error) {
+	size, compactElemType, err := cr.readCollectionHeader()
+	if err != nil {
+		return wire.Value{}, err
+	}
+	elemType, err := wireTypeFromCompact(compactElemType)
+	if err != nil {
+		return wire.Value{}, err
+	}
+
+	var items []wire.Value
+	if size > 0 {
+		items = make([]wire.Value, 0, size)
+	}
+	for i := 0; i < size; i++ {
+		item, err := cr.readValue(elemType)
+		if err != nil {
+			return wire.Value{}, err
+		}
+		items = append(items, item)
+	}
+	return build(wire.ValueList{ValueType: elemType, Size: size, Items: items}), nil
+}
+
+func (cr compactReader) readMap() (wire.Value, error) {
+	size, err := cr.readVarint()
+	if err != nil {
+		return wire.Value{}, err
+	}
+	if size == 0 {
+		return wire.NewValueMap(wire.MapItemList{}), nil
+	}
+
+	typesByte, err := cr.readByte()
+	if err != nil {
+		return wire.Value{}, err
+	}
+	keyType, err := wireTypeFromCompact(typesByte >> 4)
+	if err != nil {
+		return wire.Value{}, err
+	}
+	valueType, err := wireTypeFromCompact(typesByte & 0x0F)
+	if err != nil {
+		return wire.Value{}, err
+	}
+
+	items := make([]wire.MapItem, 0, size)
+	for i := uint64(0); i < size; i++ {
+		key, err := cr.readValue(keyType)
+		if err != nil {
+			return wire.Value{}, err
+		}
+		value, err := cr.readValue(valueType)
+		if err != nil {
+			return wire.Value{}, err
+		}
+		items = append(items, wire.MapItem{Key: key, Value: value})
+	}
+	return wire.NewValueMap(wire.MapItemList{
+		KeyType:   keyType,
+		ValueType: valueType,
+		Size:      int(size),
+		Items:     items,
+	}), nil
+}
@@ -0,0 +1,159 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package protocol
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/uber/thriftrw-go/wire"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var encodeDecodeCompactTests = []struct {
+	value    wire.Value
+	expected []byte
+
+	// skipDecode is set for vectors where the compact wire format
+	// itself discards information (e.g. the empty-map short form drops
+	// the key/value types), so decoding cannot reproduce a value equal
+	// to the original.
+	skipDecode bool
+}{
+	// bool: outside a struct field header, encoded as its compact type
+	// byte (BOOLEAN_TRUE/BOOLEAN_FALSE) rather than a plain 0/1 flag.
+	{vbool(false), []byte{0x02}, false},
+	{vbool(true), []byte{0x01}, false},
+
+	// byte
+	{vbyte(0), []byte{0x00}, false},
+	{vbyte(-1), []byte{0xff}, false},
+
+	// i16 = zigzag varint
+	{vi16(0), []byte{0x00}, false},
+	{vi16(1), []byte{0x02}, false},
+	{vi16(-1), []byte{0x01}, false},
+	{vi16(63), []byte{0x7e}, false},
+	{vi16(64), []byte{0x80, 0x01}, false},
+
+	// i32 = zigzag varint
+	{vi32(0), []byte{0x00}, false},
+	{vi32(-1), []byte{0x01}, false},
+
+	// i64 = zigzag varint
+	{vi64(0), []byte{0x00}, false},
+	{vi64(-1), []byte{0x01}, false},
+
+	// binary = varint length + bytes
+	{vbinary(""), []byte{0x00}, false},
+	{vbinary("hi"), []byte{0x02, 0x68, 0x69}, false},
+
+	// struct = (header byte (delta:4 type:4) [zigzag id] value)* stop
+	{vstruct(), []byte{0x00}, false},
+	{
+		vstruct(vfield(1, vbool(true))),
+		[]byte{0x11, 0x00}, // delta:1 type:boolean-true, stop
+		false,
+	},
+	{
+		vstruct(vfield(1, vbool(false))),
+		[]byte{0x12, 0x00}, // delta:1 type:boolean-false, stop
+		false,
+	},
+	{
+		vstruct(
+			vfield(1, vi16(42)),
+			vfield(2, vlist(wire.TBinary, vbinary("foo"), vbinary("bar"))),
+		),
+		[]byte{
+			0x14, 0x54, // delta:1 type:i16, zigzag(42) = 84
+			0x19,                         // delta:1 type:list
+			0x28, 0x03, 'f', 'o', 'o', // list header (size:2,type:binary), "foo"
+			0x03, 'b', 'a', 'r', // "bar"
+			0x00, // stop
+		},
+		false,
+	},
+
+	// set and list headers pack size and element type into one byte
+	// when the size is 14 or less. Bool's compact type is
+	// compactBooleanTrue (0x01), same as in a struct field header.
+	{vset(wire.TBool), []byte{0x01}, false},
+	{vlist(wire.TStruct), []byte{0x0C}, false},
+
+	// map: empty maps are a single zero byte, which carries no
+	// key/value type information to decode back out.
+	{vmap(wire.TI64, wire.TBinary), []byte{0x00}, true},
+}
+
+func TestCompactEncode(t *testing.T) {
+	for _, tt := range encodeDecodeCompactTests {
+		buffer := bytes.Buffer{}
+		err := Compact.Encode(tt.value, &buffer)
+		if assert.NoError(t, err, "Encode failed:\n%s", tt.value) {
+			assert.Equal(t, tt.expected, buffer.Bytes())
+		}
+	}
+}
+
+func TestCompactDecode(t *testing.T) {
+	for _, tt := range encodeDecodeCompactTests {
+		if tt.skipDecode {
+			continue
+		}
+		value, err := Compact.Decode(bytes.NewReader(tt.expected), tt.value.Type())
+		if assert.NoError(t, err, "Decode failed:\n%s", tt.expected) {
+			assert.Equal(t, tt.value, value)
+		}
+	}
+}
+
+// TestCompactRoundTrip exercises values that aren't pinned to a specific
+// byte layout above but must still survive an encode/decode cycle.
+func TestCompactRoundTrip(t *testing.T) {
+	tests := []wire.Value{
+		vdouble(3.141592653589793),
+		vmap(
+			wire.TBinary, wire.TList,
+			vitem(vbinary("a"), vlist(wire.TI16, vi16(1))),
+			vitem(vbinary("b"), vlist(wire.TI16, vi16(2), vi16(3))),
+		),
+		vset(wire.TBool, vbool(true), vbool(false), vbool(true)),
+		vlist(
+			wire.TStruct,
+			vstruct(vfield(1, vi16(1)), vfield(2, vi32(2))),
+			vstruct(vfield(1, vi16(3)), vfield(2, vi32(4))),
+		),
+	}
+
+	for _, value := range tests {
+		buffer := bytes.Buffer{}
+		if !assert.NoError(t, Compact.Encode(value, &buffer), "Encode failed:\n%s", value) {
+			continue
+		}
+
+		got, err := Compact.Decode(&buffer, value.Type())
+		if assert.NoError(t, err, "Decode failed for:\n%s", value) {
+			assert.Equal(t, value, got)
+		}
+	}
+}
@@ -0,0 +1,514 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package protocol
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+
+	"github.com/uber/thriftrw-go/wire"
+)
+
+// JSON implements the Apache Thrift JSON Protocol. The top-level value
+// and every struct field are encoded as a compact "[type, value]" pair,
+// where type is one of the short tags below; collection elements and
+// map entries carry only their payload, typed by the collection's
+// header. This makes the output self-describing and lets it
+// double as a human-readable format for logging, debugging, and
+// polyglot test fixtures.
+//
+// JSON produces single-line output. PrettyJSON produces the same tree
+// indented for diffing.
+var JSON Protocol = jsonProtocol{}
+
+// PrettyJSON is JSON with indentation, for output meant to be read by
+// humans or diffed.
+var PrettyJSON Protocol = jsonProtocol{pretty: true}
+
+// jsonTag is the short type tag Apache Thrift's JSON Protocol uses in
+// place of a wire.Type byte.
+const (
+	jsonTagBool   = "tf"
+	jsonTagByte   = "i8"
+	jsonTagI16    = "i16"
+	jsonTagI32    = "i32"
+	jsonTagI64    = "i64"
+	jsonTagDouble = "dbl"
+	jsonTagBinary = "str"
+	jsonTagStruct = "rec"
+	jsonTagMap    = "map"
+	jsonTagSet    = "set"
+	jsonTagList   = "lst"
+)
+
+func jsonTagFromType(t wire.Type) (string, error) {
+	switch t {
+	case wire.TBool:
+		return jsonTagBool, nil
+	case wire.TByte:
+		return jsonTagByte, nil
+	case wire.TI16:
+		return jsonTagI16, nil
+	case wire.TI32:
+		return jsonTagI32, nil
+	case wire.TI64:
+		return jsonTagI64, nil
+	case wire.TDouble:
+		return jsonTagDouble, nil
+	case wire.TBinary:
+		return jsonTagBinary, nil
+	case wire.TStruct:
+		return jsonTagStruct, nil
+	case wire.TMap:
+		return jsonTagMap, nil
+	case wire.TSet:
+		return jsonTagSet, nil
+	case wire.TList:
+		return jsonTagList, nil
+	default:
+		return "", fmt.Errorf("json: unknown type %v", t)
+	}
+}
+
+func typeFromJSONTag(tag string) (wire.Type, error) {
+	switch tag {
+	case jsonTagBool:
+		return wire.TBool, nil
+	case jsonTagByte:
+		return wire.TByte, nil
+	case jsonTagI16:
+		return wire.TI16, nil
+	case jsonTagI32:
+		return wire.TI32, nil
+	case jsonTagI64:
+		return wire.TI64, nil
+	case jsonTagDouble:
+		return wire.TDouble, nil
+	case jsonTagBinary:
+		return wire.TBinary, nil
+	case jsonTagStruct:
+		return wire.TStruct, nil
+	case jsonTagMap:
+		return wire.TMap, nil
+	case jsonTagSet:
+		return wire.TSet, nil
+	case jsonTagList:
+		return wire.TList, nil
+	default:
+		return 0, fmt.Errorf("json: unknown type tag %q", tag)
+	}
+}
+
+type jsonProtocol struct{ pretty bool }
+
+func (p jsonProtocol) Encode(value wire.Value, writer io.Writer) error {
+	tree, err := encodeJSONValue(value)
+	if err != nil {
+		return err
+	}
+
+	var data []byte
+	if p.pretty {
+		data, err = json.MarshalIndent(tree, "", "  ")
+	} else {
+		data, err = json.Marshal(tree)
+	}
+	if err != nil {
+		return err
+	}
+
+	_, err = writer.Write(data)
+	return err
+}
+
+func (jsonProtocol) Decode(reader io.Reader, t wire.Type) (wire.Value, error) {
+	dec := json.NewDecoder(reader)
+	// Without UseNumber, every JSON number decodes as a float64, which
+	// cannot represent the full range of an int64 exactly; i64/i32
+	// fields would silently lose precision on the round trip.
+	dec.UseNumber()
+
+	var tree interface{}
+	if err := dec.Decode(&tree); err != nil {
+		return wire.Value{}, err
+	}
+
+	pair, ok := tree.([]interface{})
+	if !ok || len(pair) != 2 {
+		return wire.Value{}, fmt.Errorf("json: expected a [type, value] pair, got %#v", tree)
+	}
+
+	tag, ok := pair[0].(string)
+	if !ok {
+		return wire.Value{}, fmt.Errorf("json: expected a type tag, got %#v", pair[0])
+	}
+
+	typ, err := typeFromJSONTag(tag)
+	if err != nil {
+		return wire.Value{}, err
+	}
+	if typ != t {
+		return wire.Value{}, fmt.Errorf("json: expected type %v, got %v", t, typ)
+	}
+
+	return decodeJSONPayload(typ, pair[1])
+}
+
+// encodeJSONValue encodes a full Value as a "[type, payload]" pair.
+func encodeJSONValue(value wire.Value) (interface{}, error) {
+	tag, err := jsonTagFromType(value.Type())
+	if err != nil {
+		return nil, err
+	}
+
+	payload, err := encodeJSONPayload(value)
+	if err != nil {
+		return nil, err
+	}
+
+	return []interface{}{tag, payload}, nil
+}
+
+// encodeJSONPayload encodes just the value half of a "[type, payload]"
+// pair; the type is implied by the caller's context (a struct field's
+// own pair, a collection's element type, ...).
+func encodeJSONPayload(value wire.Value) (interface{}, error) {
+	switch value.Type() {
+	case wire.TBool:
+		if value.GetBool() {
+			return 1, nil
+		}
+		return 0, nil
+	case wire.TByte:
+		return int64(value.GetByte()), nil
+	case wire.TI16:
+		return int64(value.GetI16()), nil
+	case wire.TI32:
+		return int64(value.GetI32()), nil
+	case wire.TI64:
+		return value.GetI64(), nil
+	case wire.TDouble:
+		return encodeJSONDouble(value.GetDouble()), nil
+	case wire.TBinary:
+		return base64.StdEncoding.EncodeToString(value.GetBinary()), nil
+	case wire.TStruct:
+		return encodeJSONStruct(value.GetStruct())
+	case wire.TMap:
+		return encodeJSONMap(value.GetMap())
+	case wire.TSet:
+		return encodeJSONList(value.GetSet())
+	case wire.TList:
+		return encodeJSONList(value.GetList())
+	default:
+		return nil, fmt.Errorf("json: cannot encode unknown type %v", value.Type())
+	}
+}
+
+// encodeJSONDouble represents NaN and the infinities as strings, since
+// the JSON number grammar has no room for them.
+func encodeJSONDouble(d float64) interface{} {
+	switch {
+	case math.IsNaN(d):
+		return "nan"
+	case math.IsInf(d, 1):
+		return "inf"
+	case math.IsInf(d, -1):
+		return "-inf"
+	default:
+		return d
+	}
+}
+
+// decodeJSONInt parses a json.Number payload as an int64, preserving
+// full i64 precision (unlike a float64 round trip through the
+// interface{} tree).
+func decodeJSONInt(payload interface{}) (int64, error) {
+	num, ok := payload.(json.Number)
+	if !ok {
+		return 0, fmt.Errorf("json: invalid integer %#v", payload)
+	}
+	return num.Int64()
+}
+
+func decodeJSONDouble(payload interface{}) (wire.Value, error) {
+	switch v := payload.(type) {
+	case json.Number:
+		f, err := v.Float64()
+		if err != nil {
+			return wire.Value{}, err
+		}
+		return wire.NewValueDouble(f), nil
+	case string:
+		switch v {
+		case "nan":
+			return wire.NewValueDouble(math.NaN()), nil
+		case "inf":
+			return wire.NewValueDouble(math.Inf(1)), nil
+		case "-inf":
+			return wire.NewValueDouble(math.Inf(-1)), nil
+		}
+	}
+	return wire.Value{}, fmt.Errorf("json: invalid double %#v", payload)
+}
+
+// encodeJSONStruct produces {"<fieldID>": [type, payload], ...}.
+func encodeJSONStruct(s wire.Struct) (interface{}, error) {
+	fields := make(map[string]interface{}, len(s.Fields))
+	for _, field := range s.Fields {
+		encoded, err := encodeJSONValue(field.Value)
+		if err != nil {
+			return nil, err
+		}
+		fields[strconv.Itoa(int(field.ID))] = encoded
+	}
+	return fields, nil
+}
+
+// encodeJSONList produces [elemType, size, payload, payload, ...].
+func encodeJSONList(l wire.ValueList) (interface{}, error) {
+	tag, err := jsonTagFromType(l.ValueType)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]interface{}, 0, len(l.Items)+2)
+	result = append(result, tag, l.Size)
+	for _, item := range l.Items {
+		payload, err := encodeJSONPayload(item)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, payload)
+	}
+	return result, nil
+}
+
+// encodeJSONMap produces [keyType, valueType, size, [key, value, key, value, ...]].
+func encodeJSONMap(m wire.MapItemList) (interface{}, error) {
+	keyTag, err := jsonTagFromType(m.KeyType)
+	if err != nil {
+		return nil, err
+	}
+	valueTag, err := jsonTagFromType(m.ValueType)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]interface{}, 0, len(m.Items)*2)
+	for _, item := range m.Items {
+		key, err := encodeJSONPayload(item.Key)
+		if err != nil {
+			return nil, err
+		}
+		value, err := encodeJSONPayload(item.Value)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, key, value)
+	}
+
+	return []interface{}{keyTag, valueTag, m.Size, entries}, nil
+}
+
+func decodeJSONPayload(t wire.Type, payload interface{}) (wire.Value, error) {
+	switch t {
+	case wire.TBool:
+		n, err := decodeJSONInt(payload)
+		if err != nil {
+			return wire.Value{}, err
+		}
+		return wire.NewValueBool(n != 0), nil
+	case wire.TByte:
+		n, err := decodeJSONInt(payload)
+		if err != nil {
+			return wire.Value{}, err
+		}
+		return wire.NewValueByte(int8(n)), nil
+	case wire.TI16:
+		n, err := decodeJSONInt(payload)
+		if err != nil {
+			return wire.Value{}, err
+		}
+		return wire.NewValueI16(int16(n)), nil
+	case wire.TI32:
+		n, err := decodeJSONInt(payload)
+		if err != nil {
+			return wire.Value{}, err
+		}
+		return wire.NewValueI32(int32(n)), nil
+	case wire.TI64:
+		n, err := decodeJSONInt(payload)
+		if err != nil {
+			return wire.Value{}, err
+		}
+		return wire.NewValueI64(n), nil
+	case wire.TDouble:
+		return decodeJSONDouble(payload)
+	case wire.TBinary:
+		s, ok := payload.(string)
+		if !ok {
+			return wire.Value{}, fmt.Errorf("json: invalid binary %#v", payload)
+		}
+		b, err := base64.StdEncoding.DecodeString(s)
+		if err != nil {
+			return wire.Value{}, err
+		}
+		return wire.NewValueBinary(b), nil
+	case wire.TStruct:
+		return decodeJSONStruct(payload)
+	case wire.TMap:
+		return decodeJSONMap(payload)
+	case wire.TSet:
+		return decodeJSONList(payload, wire.NewValueSet)
+	case wire.TList:
+		return decodeJSONList(payload, wire.NewValueList)
+	default:
+		return wire.Value{}, fmt.Errorf("json: cannot decode unknown type %v", t)
+	}
+}
+
+func decodeJSONStruct(payload interface{}) (wire.Value, error) {
+	obj, ok := payload.(map[string]interface{})
+	if !ok {
+		return wire.Value{}, fmt.Errorf("json: invalid struct %#v", payload)
+	}
+
+	fields := make([]wire.Field, 0, len(obj))
+	for idStr, raw := range obj {
+		id, err := strconv.Atoi(idStr)
+		if err != nil {
+			return wire.Value{}, fmt.Errorf("json: invalid field id %q", idStr)
+		}
+
+		pair, ok := raw.([]interface{})
+		if !ok || len(pair) != 2 {
+			return wire.Value{}, fmt.Errorf("json: invalid field value %#v", raw)
+		}
+		tag, ok := pair[0].(string)
+		if !ok {
+			return wire.Value{}, fmt.Errorf("json: invalid field type %#v", pair[0])
+		}
+
+		typ, err := typeFromJSONTag(tag)
+		if err != nil {
+			return wire.Value{}, err
+		}
+		value, err := decodeJSONPayload(typ, pair[1])
+		if err != nil {
+			return wire.Value{}, err
+		}
+
+		fields = append(fields, wire.Field{ID: int16(id), Value: value})
+	}
+
+	sort.Slice(fields, func(i, j int) bool { return fields[i].ID < fields[j].ID })
+	return wire.NewValueStruct(wire.Struct{Fields: fields}), nil
+}
+
+func decodeJSONList(payload interface{}, build func(wire.ValueList) wire.Value) (wire.Value, error) {
+	arr, ok := payload.([]interface{})
+	if !ok || len(arr) < 2 {
+		return wire.Value{}, fmt.Errorf("json: invalid collection %#v", payload)
+	}
+
+	tag, ok := arr[0].(string)
+	if !ok {
+		return wire.Value{}, fmt.Errorf("json: invalid element type %#v", arr[0])
+	}
+	elemType, err := typeFromJSONTag(tag)
+	if err != nil {
+		return wire.Value{}, err
+	}
+
+	size, err := decodeJSONInt(arr[1])
+	if err != nil {
+		return wire.Value{}, fmt.Errorf("json: invalid collection size %#v", arr[1])
+	}
+
+	items := make([]wire.Value, 0, int(size))
+	for _, raw := range arr[2:] {
+		item, err := decodeJSONPayload(elemType, raw)
+		if err != nil {
+			return wire.Value{}, err
+		}
+		items = append(items, item)
+	}
+
+	return build(wire.ValueList{ValueType: elemType, Size: int(size), Items: items}), nil
+}
+
+func decodeJSONMap(payload interface{}) (wire.Value, error) {
+	arr, ok := payload.([]interface{})
+	if !ok || len(arr) != 4 {
+		return wire.Value{}, fmt.Errorf("json: invalid map %#v", payload)
+	}
+
+	keyTag, ok := arr[0].(string)
+	if !ok {
+		return wire.Value{}, fmt.Errorf("json: invalid map key type %#v", arr[0])
+	}
+	valueTag, ok := arr[1].(string)
+	if !ok {
+		return wire.Value{}, fmt.Errorf("json: invalid map value type %#v", arr[1])
+	}
+	keyType, err := typeFromJSONTag(keyTag)
+	if err != nil {
+		return wire.Value{}, err
+	}
+	valueType, err := typeFromJSONTag(valueTag)
+	if err != nil {
+		return wire.Value{}, err
+	}
+
+	size, err := decodeJSONInt(arr[2])
+	if err != nil {
+		return wire.Value{}, fmt.Errorf("json: invalid map size %#v", arr[2])
+	}
+	entries, ok := arr[3].([]interface{})
+	if !ok {
+		return wire.Value{}, fmt.Errorf("json: invalid map entries %#v", arr[3])
+	}
+
+	items := make([]wire.MapItem, 0, int(size))
+	for i := 0; i+1 < len(entries); i += 2 {
+		key, err := decodeJSONPayload(keyType, entries[i])
+		if err != nil {
+			return wire.Value{}, err
+		}
+		value, err := decodeJSONPayload(valueType, entries[i+1])
+		if err != nil {
+			return wire.Value{}, err
+		}
+		items = append(items, wire.MapItem{Key: key, Value: value})
+	}
+
+	return wire.NewValueMap(wire.MapItemList{
+		KeyType:   keyType,
+		ValueType: valueType,
+		Size:      int(size),
+		Items:     items,
+	}), nil
+}
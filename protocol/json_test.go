@@ -0,0 +1,86 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package protocol
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/uber/thriftrw-go/wire"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJSONEncode(t *testing.T) {
+	tests := []struct {
+		value    wire.Value
+		expected string
+	}{
+		{vbool(true), `["tf",1]`},
+		{vbool(false), `["tf",0]`},
+		{vi16(42), `["i16",42]`},
+		{vbinary("hi"), `["str","aGk="]`},
+		{vstruct(vfield(1, vbool(true))), `["rec",{"1":["tf",1]}]`},
+		{vlist(wire.TI16, vi16(1), vi16(2)), `["lst",["i16",2,1,2]]`},
+	}
+
+	for _, tt := range tests {
+		buffer := bytes.Buffer{}
+		err := JSON.Encode(tt.value, &buffer)
+		assert.NoError(t, err)
+		assert.Equal(t, tt.expected, buffer.String())
+	}
+}
+
+func TestJSONPretty(t *testing.T) {
+	buffer := bytes.Buffer{}
+	assert.NoError(t, PrettyJSON.Encode(vstruct(vfield(1, vbool(true))), &buffer))
+	assert.True(t, strings.Contains(buffer.String(), "\n"), "pretty output should be indented:\n%s", buffer.String())
+}
+
+// TestJSONRoundTripWithBinary reuses the Binary Protocol's own test
+// fixtures to verify Binary.Decode -> JSON.Encode -> JSON.Decode ->
+// Binary.Encode preserves the original bytes, i.e. the two protocols
+// agree on what a wire.Value means.
+func TestJSONRoundTripWithBinary(t *testing.T) {
+	for _, tt := range encodeDecodeTests {
+		value, err := Binary.Decode(bytes.NewReader(tt.expected), tt.value.Type())
+		if !assert.NoError(t, err, "Binary.Decode failed for:\n%s", tt.value) {
+			continue
+		}
+
+		var jsonBuffer bytes.Buffer
+		if !assert.NoError(t, JSON.Encode(value, &jsonBuffer), "JSON.Encode failed for:\n%s", value) {
+			continue
+		}
+
+		roundTripped, err := JSON.Decode(&jsonBuffer, tt.value.Type())
+		if !assert.NoError(t, err, "JSON.Decode failed for:\n%s", jsonBuffer.String()) {
+			continue
+		}
+
+		var binaryBuffer bytes.Buffer
+		if assert.NoError(t, Binary.Encode(roundTripped, &binaryBuffer), "Binary.Encode failed for:\n%s", roundTripped) {
+			assert.Equal(t, tt.expected, binaryBuffer.Bytes())
+		}
+	}
+}
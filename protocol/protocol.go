@@ -0,0 +1,41 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package protocol
+
+import (
+	"io"
+
+	"github.com/uber/thriftrw-go/wire"
+)
+
+// Protocol defines a specific way for a Value to be encoded or decoded
+// to or from a byte stream. Binary, Compact, JSON and PrettyJSON are the
+// Protocol implementations provided by this package; callers that need
+// to pick between them at runtime should depend on this interface rather than
+// on a concrete implementation.
+type Protocol interface {
+	// Encode encodes the given Value and writes the result to the given
+	// Writer.
+	Encode(value wire.Value, writer io.Writer) error
+
+	// Decode reads a Value of the given type from the given Reader.
+	Decode(reader io.Reader, t wire.Type) (wire.Value, error)
+}
@@ -0,0 +1,112 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package protocol
+
+import (
+	"io"
+
+	"github.com/uber/thriftrw-go/wire"
+)
+
+// ValueReader provides pull-based decoding of a single Thrift value.
+// Unlike Protocol.Decode, it never materializes a full wire.Value tree:
+// callers read exactly the fields, elements, or entries they need, in
+// wire order, directly off the underlying io.Reader. This keeps memory
+// use bounded when decoding large payloads such as a long list of
+// structs.
+//
+// ReadFieldBegin reports ok == false once the struct's terminal field
+// has been consumed; every other Begin/End pair must be called even
+// when the corresponding wire format has no on-the-wire representation
+// for it.
+type ValueReader interface {
+	ReadStructBegin() error
+	ReadStructEnd() error
+
+	ReadFieldBegin() (id int16, typ wire.Type, ok bool, err error)
+	ReadFieldEnd() error
+
+	ReadListBegin() (typ wire.Type, size int, err error)
+	ReadListEnd() error
+
+	ReadSetBegin() (typ wire.Type, size int, err error)
+	ReadSetEnd() error
+
+	ReadMapBegin() (keyType, valueType wire.Type, size int, err error)
+	ReadMapEnd() error
+
+	ReadBool() (bool, error)
+	ReadI8() (int8, error)
+	ReadI16() (int16, error)
+	ReadI32() (int32, error)
+	ReadI64() (int64, error)
+	ReadDouble() (float64, error)
+	ReadBinary() ([]byte, error)
+}
+
+// ValueWriter is the streaming counterpart of ValueReader: it lets a
+// caller serialize a value field-by-field or element-by-element
+// without first assembling a wire.Value tree. Generated struct
+// marshalers are the primary intended user.
+//
+// WriteFieldStop must be called once, after the struct's last field
+// and before WriteStructEnd, to terminate the field list.
+type ValueWriter interface {
+	WriteStructBegin() error
+	WriteStructEnd() error
+
+	WriteFieldBegin(id int16, typ wire.Type) error
+	WriteFieldEnd() error
+	WriteFieldStop() error
+
+	WriteListBegin(typ wire.Type, size int) error
+	WriteListEnd() error
+
+	WriteSetBegin(typ wire.Type, size int) error
+	WriteSetEnd() error
+
+	WriteMapBegin(keyType, valueType wire.Type, size int) error
+	WriteMapEnd() error
+
+	WriteBool(bool) error
+	WriteI8(int8) error
+	WriteI16(int16) error
+	WriteI32(int32) error
+	WriteI64(int64) error
+	WriteDouble(float64) error
+	WriteBinary([]byte) error
+}
+
+// StreamProtocol is implemented by Protocols that additionally support
+// streaming encode/decode through ValueReader and ValueWriter, for
+// callers that can't afford to hold an entire wire.Value tree in
+// memory at once.
+type StreamProtocol interface {
+	Protocol
+
+	// Reader returns a ValueReader that pulls a single value directly
+	// off the given io.Reader.
+	Reader(io.Reader) ValueReader
+
+	// Writer returns a ValueWriter that pushes a single value directly
+	// onto the given io.Writer.
+	Writer(io.Writer) ValueWriter
+}
@@ -0,0 +1,118 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package protocol
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/uber/thriftrw-go/wire"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestBinaryStreamStruct writes a two-field struct field-by-field
+// through a ValueWriter and reads it back through a ValueReader,
+// without ever building a wire.Value tree, to exercise the pull-based
+// decoding API end to end.
+func TestBinaryStreamStruct(t *testing.T) {
+	buffer := bytes.Buffer{}
+	w := Binary.(StreamProtocol).Writer(&buffer)
+
+	assert.NoError(t, w.WriteStructBegin())
+	assert.NoError(t, w.WriteFieldBegin(1, wire.TI16))
+	assert.NoError(t, w.WriteI16(42))
+	assert.NoError(t, w.WriteFieldEnd())
+
+	assert.NoError(t, w.WriteFieldBegin(2, wire.TBinary))
+	assert.NoError(t, w.WriteBinary([]byte("hello")))
+	assert.NoError(t, w.WriteFieldEnd())
+
+	assert.NoError(t, w.WriteFieldStop())
+	assert.NoError(t, w.WriteStructEnd())
+
+	assert.Equal(t, []byte{
+		0x06,       // type:1 = i16
+		0x00, 0x01, // id:2 = 1
+		0x00, 0x2a, // value = 42
+
+		0x0B,       // type:1 = binary
+		0x00, 0x02, // id:2 = 2
+		0x00, 0x00, 0x00, 0x05, // len:4 = 5
+		0x68, 0x65, 0x6c, 0x6c, 0x6f, // "hello"
+
+		0x00, // stop
+	}, buffer.Bytes())
+
+	r := Binary.(StreamProtocol).Reader(&buffer)
+	assert.NoError(t, r.ReadStructBegin())
+
+	id, typ, ok, err := r.ReadFieldBegin()
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, int16(1), id)
+	assert.Equal(t, wire.TI16, typ)
+	i16, err := r.ReadI16()
+	assert.NoError(t, err)
+	assert.Equal(t, int16(42), i16)
+	assert.NoError(t, r.ReadFieldEnd())
+
+	id, typ, ok, err = r.ReadFieldBegin()
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, int16(2), id)
+	assert.Equal(t, wire.TBinary, typ)
+	bin, err := r.ReadBinary()
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("hello"), bin)
+	assert.NoError(t, r.ReadFieldEnd())
+
+	_, _, ok, err = r.ReadFieldBegin()
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	assert.NoError(t, r.ReadStructEnd())
+}
+
+// TestBinaryStreamList writes and reads a list of i32s to exercise the
+// collection Begin/End pairs.
+func TestBinaryStreamList(t *testing.T) {
+	buffer := bytes.Buffer{}
+	w := Binary.(StreamProtocol).Writer(&buffer)
+
+	assert.NoError(t, w.WriteListBegin(wire.TI32, 2))
+	assert.NoError(t, w.WriteI32(1))
+	assert.NoError(t, w.WriteI32(2))
+	assert.NoError(t, w.WriteListEnd())
+
+	r := Binary.(StreamProtocol).Reader(&buffer)
+	typ, size, err := r.ReadListBegin()
+	assert.NoError(t, err)
+	assert.Equal(t, wire.TI32, typ)
+	assert.Equal(t, 2, size)
+
+	for _, want := range []int32{1, 2} {
+		got, err := r.ReadI32()
+		assert.NoError(t, err)
+		assert.Equal(t, want, got)
+	}
+	assert.NoError(t, r.ReadListEnd())
+}
@@ -0,0 +1,65 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package transport
+
+import (
+	"bufio"
+	"io"
+)
+
+// DefaultBufferSize is the buffer size used when one isn't given
+// explicitly.
+const DefaultBufferSize = 4096
+
+// BufferedWriter batches the small field-at-a-time writes a Protocol
+// tends to make into fewer, larger writes to the underlying io.Writer.
+// Callers must call Flush once they're done writing a message;
+// otherwise the tail of the message may remain stuck in the buffer.
+type BufferedWriter struct {
+	*bufio.Writer
+}
+
+// NewBufferedWriter wraps w with a buffer of DefaultBufferSize bytes.
+func NewBufferedWriter(w io.Writer) *BufferedWriter {
+	return NewBufferedWriterSize(w, DefaultBufferSize)
+}
+
+// NewBufferedWriterSize wraps w with a buffer of the given size.
+func NewBufferedWriterSize(w io.Writer, size int) *BufferedWriter {
+	return &BufferedWriter{Writer: bufio.NewWriterSize(w, size)}
+}
+
+// BufferedReader batches reads off the underlying io.Reader so that a
+// Protocol reading a message byte-by-byte or field-by-field doesn't
+// make a syscall for each one.
+type BufferedReader struct {
+	*bufio.Reader
+}
+
+// NewBufferedReader wraps r with a buffer of DefaultBufferSize bytes.
+func NewBufferedReader(r io.Reader) *BufferedReader {
+	return NewBufferedReaderSize(r, DefaultBufferSize)
+}
+
+// NewBufferedReaderSize wraps r with a buffer of the given size.
+func NewBufferedReaderSize(r io.Reader, size int) *BufferedReader {
+	return &BufferedReader{Reader: bufio.NewReaderSize(r, size)}
+}
@@ -0,0 +1,178 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package transport
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/ioutil"
+)
+
+// DefaultMaxFrameSize is the frame size limit used when one isn't given
+// explicitly. It protects a server from a client that claims an
+// unreasonably large frame and would otherwise make it buffer that much
+// memory.
+const DefaultMaxFrameSize = 16 * 1024 * 1024 // 16 MiB
+
+// FrameTooLargeError is returned when a frame's length prefix (on read)
+// or accumulated size (on write) exceeds the configured max frame size.
+type FrameTooLargeError struct {
+	Size    int
+	MaxSize int
+}
+
+func (e FrameTooLargeError) Error() string {
+	return fmt.Sprintf("transport: frame of size %d exceeds the maximum of %d bytes", e.Size, e.MaxSize)
+}
+
+// FramedReader reads a stream of 4-byte-big-endian-length-prefixed
+// frames off the underlying io.Reader, presenting them to callers as a
+// single io.Reader whose reads stop at each frame boundary: once a
+// frame has been fully consumed, Read returns io.EOF rather than
+// silently moving on to the next frame's length prefix. A Decode call
+// against a FramedReader consumes exactly one message; call NextFrame
+// before reading the following message.
+type FramedReader struct {
+	reader       io.Reader
+	maxFrameSize int
+	frameOpen    bool
+	remaining    int
+}
+
+// NewFramedReader returns a FramedReader that rejects frames larger
+// than DefaultMaxFrameSize.
+func NewFramedReader(r io.Reader) *FramedReader {
+	return NewFramedReaderSize(r, DefaultMaxFrameSize)
+}
+
+// NewFramedReaderSize returns a FramedReader that rejects frames larger
+// than maxFrameSize.
+func NewFramedReaderSize(r io.Reader, maxFrameSize int) *FramedReader {
+	return &FramedReader{reader: r, maxFrameSize: maxFrameSize}
+}
+
+// Read implements io.Reader. The first Read reads the frame's length
+// prefix before serving any of its bytes. Once those bytes are
+// exhausted, Read returns io.EOF; call NextFrame to move on to the
+// following message.
+func (r *FramedReader) Read(p []byte) (int, error) {
+	if !r.frameOpen {
+		if err := r.readFrameHeader(); err != nil {
+			return 0, err
+		}
+	}
+
+	if r.remaining == 0 {
+		return 0, io.EOF
+	}
+
+	if len(p) > r.remaining {
+		p = p[:r.remaining]
+	}
+
+	n, err := r.reader.Read(p)
+	r.remaining -= n
+	return n, err
+}
+
+// NextFrame discards any bytes of the current frame that weren't read
+// and readies the FramedReader to read the next frame's length prefix
+// on the following Read call. It must be called between messages when
+// reusing a single FramedReader for more than one frame.
+func (r *FramedReader) NextFrame() error {
+	if r.remaining > 0 {
+		if _, err := io.CopyN(ioutil.Discard, r.reader, int64(r.remaining)); err != nil {
+			return err
+		}
+		r.remaining = 0
+	}
+	r.frameOpen = false
+	return nil
+}
+
+func (r *FramedReader) readFrameHeader() error {
+	var header [4]byte
+	if _, err := io.ReadFull(r.reader, header[:]); err != nil {
+		return err
+	}
+
+	// Compare before truncating to int: on a 32-bit build, a size
+	// above math.MaxInt32 would otherwise wrap to a negative int and
+	// pass the size > maxFrameSize check, leaving Read to panic when it
+	// slices p by a negative r.remaining.
+	size := binary.BigEndian.Uint32(header[:])
+	if int64(size) > int64(r.maxFrameSize) {
+		return FrameTooLargeError{Size: int(size), MaxSize: r.maxFrameSize}
+	}
+
+	r.frameOpen = true
+	r.remaining = int(size)
+	return nil
+}
+
+// FramedWriter buffers everything written to it and, once Flush is
+// called, writes it to the underlying io.Writer as a single frame: a
+// 4-byte big-endian length prefix followed by the buffered bytes. An
+// Encode call followed by Flush produces exactly one frame.
+type FramedWriter struct {
+	writer       io.Writer
+	buffer       bytes.Buffer
+	maxFrameSize int
+}
+
+// NewFramedWriter returns a FramedWriter that rejects frames larger
+// than DefaultMaxFrameSize.
+func NewFramedWriter(w io.Writer) *FramedWriter {
+	return NewFramedWriterSize(w, DefaultMaxFrameSize)
+}
+
+// NewFramedWriterSize returns a FramedWriter that rejects frames larger
+// than maxFrameSize.
+func NewFramedWriterSize(w io.Writer, maxFrameSize int) *FramedWriter {
+	return &FramedWriter{writer: w, maxFrameSize: maxFrameSize}
+}
+
+// Write implements io.Writer. The bytes are buffered in memory until
+// Flush is called.
+func (w *FramedWriter) Write(p []byte) (int, error) {
+	return w.buffer.Write(p)
+}
+
+// Flush writes the buffered bytes to the underlying io.Writer as a
+// single length-prefixed frame and resets the buffer for the next
+// message.
+func (w *FramedWriter) Flush() error {
+	size := w.buffer.Len()
+	if size > w.maxFrameSize {
+		w.buffer.Reset()
+		return FrameTooLargeError{Size: size, MaxSize: w.maxFrameSize}
+	}
+
+	var header [4]byte
+	binary.BigEndian.PutUint32(header[:], uint32(size))
+	if _, err := w.writer.Write(header[:]); err != nil {
+		return err
+	}
+	_, err := w.buffer.WriteTo(w.writer)
+	return err
+}
@@ -0,0 +1,114 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package transport
+
+import (
+	"bytes"
+	"io/ioutil"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFramedWriterReader(t *testing.T) {
+	var buffer bytes.Buffer
+
+	w := NewFramedWriter(&buffer)
+	_, err := w.Write([]byte("hello"))
+	assert.NoError(t, err)
+	assert.NoError(t, w.Flush())
+
+	w2 := NewFramedWriter(&buffer)
+	_, err = w2.Write([]byte("world!"))
+	assert.NoError(t, err)
+	assert.NoError(t, w2.Flush())
+
+	assert.Equal(t, []byte{
+		0x00, 0x00, 0x00, 0x05, 'h', 'e', 'l', 'l', 'o',
+		0x00, 0x00, 0x00, 0x06, 'w', 'o', 'r', 'l', 'd', '!',
+	}, buffer.Bytes())
+
+	r := NewFramedReader(&buffer)
+	got, err := ioutil.ReadAll(r)
+	assert.NoError(t, err)
+	assert.Equal(t, "hello", string(got))
+
+	r2 := NewFramedReader(&buffer)
+	got, err = ioutil.ReadAll(r2)
+	assert.NoError(t, err)
+	assert.Equal(t, "world!", string(got))
+}
+
+// TestFramedReaderSingleInstanceAcrossFrames verifies that a single
+// FramedReader, rather than one-per-message, stops at each frame
+// boundary (io.EOF) and only moves on to the next frame's header once
+// NextFrame is called.
+func TestFramedReaderSingleInstanceAcrossFrames(t *testing.T) {
+	var buffer bytes.Buffer
+	w := NewFramedWriter(&buffer)
+	for _, msg := range []string{"hello", "world!"} {
+		_, err := w.Write([]byte(msg))
+		assert.NoError(t, err)
+		assert.NoError(t, w.Flush())
+	}
+
+	r := NewFramedReader(&buffer)
+
+	got, err := ioutil.ReadAll(r)
+	assert.NoError(t, err)
+	assert.Equal(t, "hello", string(got))
+
+	assert.NoError(t, r.NextFrame())
+
+	got, err = ioutil.ReadAll(r)
+	assert.NoError(t, err)
+	assert.Equal(t, "world!", string(got))
+}
+
+func TestFramedReaderRejectsOversizedFrame(t *testing.T) {
+	buffer := bytes.NewBuffer([]byte{0x00, 0x00, 0x00, 0x10}) // claims 16 bytes
+	r := NewFramedReaderSize(buffer, 8)
+
+	_, err := r.Read(make([]byte, 1))
+	assert.Equal(t, FrameTooLargeError{Size: 16, MaxSize: 8}, err)
+}
+
+// TestFramedReaderRejectsHeaderNearUint32Max guards against the
+// header's uint32 size wrapping to a negative int (and panicking a
+// slice expression in Read) before the size-limit check runs.
+func TestFramedReaderRejectsHeaderNearUint32Max(t *testing.T) {
+	buffer := bytes.NewBuffer([]byte{0xFF, 0xFF, 0xFF, 0xFF}) // claims 4294967295 bytes
+	r := NewFramedReaderSize(buffer, 8)
+
+	_, err := r.Read(make([]byte, 1))
+	assert.Equal(t, FrameTooLargeError{Size: 4294967295, MaxSize: 8}, err)
+}
+
+func TestFramedWriterRejectsOversizedFrame(t *testing.T) {
+	var buffer bytes.Buffer
+	w := NewFramedWriterSize(&buffer, 4)
+
+	_, err := w.Write([]byte("too long"))
+	assert.NoError(t, err) // buffering never fails
+
+	err = w.Flush()
+	assert.Equal(t, FrameTooLargeError{Size: 8, MaxSize: 4}, err)
+}